@@ -0,0 +1,188 @@
+package ipldzec
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+
+	cid "github.com/ipfs/go-cid"
+	node "github.com/ipfs/go-ipld-format"
+)
+
+// Block is a full Zcash block: a Header plus the transactions it commits
+// to. Its Cid is the Zcash block hash -- the hash of the header alone --
+// so a Block and its embedded Header share the same CID.
+type Block struct {
+	Header *Header `json:"header"`
+	Txs    []*Tx   `json:"tx"`
+}
+
+// NewBlock returns an empty Block whose Header is addressed under codec
+// instead of DefaultZcashCodec. Use the zero Codec{} to get the default
+// behavior.
+func NewBlock(codec Codec) *Block {
+	return &Block{Header: NewHeader(codec)}
+}
+
+// DecodeBlock parses the p2p wire format of a Zcash block: a header
+// followed by a CompactSize transaction count and that many serialized
+// transactions.
+func DecodeBlock(r io.Reader) (*Block, error) {
+	return DecodeBlockWithCodec(r, DefaultZcashCodec)
+}
+
+// DecodeBlockWithCodec is DecodeBlock, but addresses the resulting Block's
+// Header and transactions under codec instead of DefaultZcashCodec.
+func DecodeBlockWithCodec(r io.Reader, codec Codec) (*Block, error) {
+	h, err := DecodeHeaderWithCodec(r, codec)
+	if err != nil {
+		return nil, err
+	}
+
+	nTx, err := readCount(r)
+	if err != nil {
+		return nil, err
+	}
+
+	txs := make([]*Tx, nTx)
+	for i := range txs {
+		if txs[i], err = DecodeTxWithCodec(r, codec); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Block{Header: h, Txs: txs}, nil
+}
+
+// DecodeBlockBytes is a convenience wrapper around DecodeBlock for callers
+// that already have the full block in memory.
+func DecodeBlockBytes(b []byte) (*Block, error) {
+	return DecodeBlock(bytes.NewReader(b))
+}
+
+func (b *Block) RawData() []byte {
+	buf := new(bytes.Buffer)
+	buf.Write(b.Header.RawData())
+	writeVarInt(buf, uint64(len(b.Txs)))
+	for _, tx := range b.Txs {
+		buf.Write(tx.RawData())
+	}
+	return buf.Bytes()
+}
+
+// Cid is the Zcash block hash: the hash of the header only, matching
+// zcashd's notion of a block's identity.
+func (b *Block) Cid() *cid.Cid {
+	return b.Header.Cid()
+}
+
+func (b *Block) HexHash() string {
+	return b.Header.HexHash()
+}
+
+func (b *Block) Links() []*node.Link {
+	out := []*node.Link{{Name: "header", Cid: b.Header.Cid()}}
+	for i, tx := range b.Txs {
+		out = append(out, &node.Link{Name: fmt.Sprintf("tx/%d", i), Cid: tx.Cid()})
+	}
+	return out
+}
+
+func (b *Block) Loggable() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "zcashBlock",
+	}
+}
+
+func (b *Block) Resolve(path []string) (interface{}, []string, error) {
+	switch path[0] {
+	case "header":
+		if len(path) == 1 {
+			return &node.Link{Name: "header", Cid: b.Header.Cid()}, nil, nil
+		}
+		return b.Header.Resolve(path[1:])
+	case "tx":
+		if len(path) == 1 {
+			return b.Txs, nil, nil
+		}
+
+		index, err := strconv.Atoi(path[1])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if index >= len(b.Txs) || index < 0 {
+			return nil, nil, fmt.Errorf("index out of range")
+		}
+
+		tx := b.Txs[index]
+		if len(path) == 2 {
+			return &node.Link{Name: fmt.Sprintf("tx/%d", index), Cid: tx.Cid()}, nil, nil
+		}
+
+		return tx.Resolve(path[2:])
+	default:
+		return nil, nil, fmt.Errorf("no such link")
+	}
+}
+
+func (b *Block) ResolveLink(path []string) (*node.Link, []string, error) {
+	i, rest, err := b.Resolve(path)
+	if err != nil {
+		return nil, rest, err
+	}
+
+	lnk, ok := i.(*node.Link)
+	if !ok {
+		return nil, nil, fmt.Errorf("value was not a link")
+	}
+
+	return lnk, rest, nil
+}
+
+func (b *Block) Size() (uint64, error) {
+	return uint64(len(b.RawData())), nil
+}
+
+func (b *Block) Stat() (*node.NodeStat, error) {
+	return &node.NodeStat{}, nil
+}
+
+func (b *Block) Copy() node.Node {
+	nb := *b
+	return &nb
+}
+
+func (b *Block) String() string {
+	return fmt.Sprintf("zcash block")
+}
+
+func (b *Block) Tree(p string, depth int) []string {
+	if depth == 0 {
+		return nil
+	}
+
+	switch p {
+	case "":
+		out := []string{"header"}
+		return b.treeTxs(out, depth)
+	case "tx":
+		return b.treeTxs(nil, depth+1)
+	default:
+		return nil
+	}
+}
+
+func (b *Block) treeTxs(out []string, depth int) []string {
+	if depth < 2 {
+		return out
+	}
+
+	for i := range b.Txs {
+		out = append(out, "tx/"+strconv.Itoa(i))
+	}
+	return out
+}
+
+var _ node.Node = (*Block)(nil)
@@ -0,0 +1,76 @@
+package ipldzec
+
+import (
+	"bytes"
+	"testing"
+)
+
+func fixtureHeader() *Header {
+	return &Header{
+		Version:       4,
+		MerkleRoot:    bytes.Repeat([]byte{0xaa}, 32),
+		ReservedField: bytes.Repeat([]byte{0xbb}, 32),
+		Timestamp:     1580000000,
+		Bits:          0x1d00ffff,
+		Nonce:         bytes.Repeat([]byte{0xcc}, 32),
+		SolutionSize:  equihashSolutionSize,
+		Solution:      bytes.Repeat([]byte{0xdd}, equihashSolutionSize),
+	}
+}
+
+func TestHeaderDecodeRawDataRoundTrip(t *testing.T) {
+	want := fixtureHeader()
+	raw := want.RawData()
+
+	if len(raw) != 1487 {
+		t.Fatalf("expected a 1487-byte header, got %d bytes", len(raw))
+	}
+
+	got, err := DecodeHeader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("DecodeHeader: %v", err)
+	}
+
+	if !bytes.Equal(got.RawData(), raw) {
+		t.Fatalf("decoded header re-encodes differently")
+	}
+
+	if want.Cid().String() != got.Cid().String() {
+		t.Fatalf("header Cid() not stable across decode round-trip")
+	}
+}
+
+func TestBlockDecodeRoundTrip(t *testing.T) {
+	want := &Block{
+		Header: fixtureHeader(),
+		Txs: []*Tx{
+			fixtureSaplingTx(),
+			{
+				Version: 1,
+				Inputs: []*TxIn{
+					{PrevTxIndex: 0xffffffff, Script: []byte{0x03, 0x01, 0x02, 0x03}, SeqNo: 0xffffffff},
+				},
+				Outputs: []*TxOut{
+					{Value: 1250000000, Script: []byte{0x76, 0xa9, 0x14}},
+				},
+			},
+		},
+	}
+
+	got, err := DecodeBlockBytes(want.RawData())
+	if err != nil {
+		t.Fatalf("DecodeBlockBytes: %v", err)
+	}
+
+	if len(got.Txs) != len(want.Txs) {
+		t.Fatalf("expected %d txs, got %d", len(want.Txs), len(got.Txs))
+	}
+
+	if !bytes.Equal(got.RawData(), want.RawData()) {
+		t.Fatalf("decoded block re-encodes differently")
+	}
+
+	if want.Cid().String() != got.Cid().String() {
+		t.Fatalf("block Cid() not stable across decode round-trip")
+	}
+}
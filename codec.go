@@ -0,0 +1,38 @@
+package ipldzec
+
+import (
+	cid "github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// Codec selects the CID codecs and multihash function a Tx or Block is
+// addressed with, so downstream users can produce alternate encodings
+// (e.g. a single-SHA2-256 variant used by some indexers) without forking
+// this package.
+type Codec struct {
+	TxCodec    uint64
+	BlockCodec uint64
+	Hash       uint64
+}
+
+// DefaultZcashCodec is the Codec every Tx/Header/Block uses unless
+// constructed otherwise, matching zcashd's own CIDs.
+var DefaultZcashCodec = Codec{
+	TxCodec:    cid.ZcashTx,
+	BlockCodec: cid.ZcashBlock,
+	Hash:       mh.DBL_SHA2_256,
+}
+
+// orDefault fills any zero-valued field of c from DefaultZcashCodec.
+func (c Codec) orDefault() Codec {
+	if c.TxCodec == 0 {
+		c.TxCodec = DefaultZcashCodec.TxCodec
+	}
+	if c.BlockCodec == 0 {
+		c.BlockCodec = DefaultZcashCodec.BlockCodec
+	}
+	if c.Hash == 0 {
+		c.Hash = DefaultZcashCodec.Hash
+	}
+	return c
+}
@@ -0,0 +1,126 @@
+package ipldzec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	cid "github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// writeVarInt writes i to w using the Bitcoin/Zcash CompactSize encoding.
+func writeVarInt(w io.Writer, i uint64) (int, error) {
+	switch {
+	case i < 0xfd:
+		return w.Write([]byte{byte(i)})
+	case i <= 0xffff:
+		buf := make([]byte, 3)
+		buf[0] = 0xfd
+		binary.LittleEndian.PutUint16(buf[1:], uint16(i))
+		return w.Write(buf)
+	case i <= 0xffffffff:
+		buf := make([]byte, 5)
+		buf[0] = 0xfe
+		binary.LittleEndian.PutUint32(buf[1:], uint32(i))
+		return w.Write(buf)
+	default:
+		buf := make([]byte, 9)
+		buf[0] = 0xff
+		binary.LittleEndian.PutUint64(buf[1:], i)
+		return w.Write(buf)
+	}
+}
+
+// readVarInt reads a Bitcoin/Zcash CompactSize encoded integer from r.
+func readVarInt(r io.Reader) (uint64, error) {
+	var prefix [1]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return 0, err
+	}
+
+	switch prefix[0] {
+	case 0xfd:
+		var buf [2]byte
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return 0, err
+		}
+		return uint64(binary.LittleEndian.Uint16(buf[:])), nil
+	case 0xfe:
+		var buf [4]byte
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return 0, err
+		}
+		return uint64(binary.LittleEndian.Uint32(buf[:])), nil
+	case 0xff:
+		var buf [8]byte
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return 0, err
+		}
+		return binary.LittleEndian.Uint64(buf[:]), nil
+	default:
+		return uint64(prefix[0]), nil
+	}
+}
+
+// maxWireCount bounds the element counts and lengths read from CompactSize
+// fields on the wire. It is far above anything a real Zcash block or
+// transaction encodes, so it only rejects corrupt or adversarial input
+// before it drives an allocation or slice length.
+const maxWireCount = 1 << 24
+
+// readCount reads a CompactSize value and checks it against maxWireCount,
+// for callers that use the result to size a make() or readFixed call.
+func readCount(r io.Reader) (uint64, error) {
+	n, err := readVarInt(r)
+	if err != nil {
+		return 0, err
+	}
+	if n > maxWireCount {
+		return 0, fmt.Errorf("wire count %d exceeds sanity limit of %d", n, maxWireCount)
+	}
+	return n, nil
+}
+
+// revString reverses a byte slice, used to flip between the internal
+// little-endian hash byte order and the big-endian order used for display
+// and RPC output.
+func revString(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i, c := range b {
+		out[len(b)-1-i] = c
+	}
+	return out
+}
+
+// errWriter wraps an io.Writer, tracking total bytes written and the
+// first error encountered so that a long chain of Write calls in a
+// streaming encoder doesn't need to check an error after each one.
+type errWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (e *errWriter) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	n, err := e.w.Write(p)
+	e.n += int64(n)
+	if err != nil {
+		e.err = err
+	}
+	return n, err
+}
+
+// cidToHash extracts the raw (little-endian, internal byte order) hash
+// digest backing a CID, for embedding in the wire-format encoding of a
+// previous-transaction reference.
+func cidToHash(c *cid.Cid) []byte {
+	dec, err := mh.Decode(c.Hash())
+	if err != nil {
+		return nil
+	}
+	return dec.Digest
+}
@@ -0,0 +1,247 @@
+package ipldzec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	cid "github.com/ipfs/go-cid"
+	node "github.com/ipfs/go-ipld-format"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// equihashSolutionSize is the length, in bytes, of the Equihash (n=200,
+// k=9) solution carried by every Zcash block header.
+const equihashSolutionSize = 1344
+
+// Header is a Zcash block header. Its Cid() is the canonical Zcash block
+// hash: DBL_SHA2_256 over the 1487-byte serialized header, which includes
+// the Equihash solution.
+type Header struct {
+	Version int32 `json:"version"`
+
+	Parent *cid.Cid `json:"parent,omitempty"`
+
+	MerkleRoot []byte `json:"merkleRoot"`
+
+	// ReservedField holds the Zcash "reserved" header field. From
+	// Sapling onward it carries hashFinalSaplingRoot.
+	ReservedField []byte `json:"reserved"`
+
+	Timestamp uint32 `json:"timestamp"`
+	Bits      uint32 `json:"bits"`
+	Nonce     []byte `json:"nonce"`
+
+	SolutionSize uint64 `json:"solutionSize"`
+	Solution     []byte `json:"solution"`
+
+	// codec selects the CID codec/multihash h is addressed with. The
+	// zero value falls back to DefaultZcashCodec.
+	codec Codec
+}
+
+// NewHeader returns an empty Header addressed under codec instead of
+// DefaultZcashCodec. Use the zero Codec{} to get the default behavior.
+func NewHeader(codec Codec) *Header {
+	return &Header{codec: codec}
+}
+
+// DecodeHeader parses a serialized Zcash block header from r.
+func DecodeHeader(r io.Reader) (*Header, error) {
+	return DecodeHeaderWithCodec(r, DefaultZcashCodec)
+}
+
+// DecodeHeaderWithCodec is DecodeHeader, but addresses the resulting
+// Header (and its parent link) under codec instead of DefaultZcashCodec.
+func DecodeHeaderWithCodec(r io.Reader, codec Codec) (*Header, error) {
+	h := &Header{codec: codec}
+
+	var i32 [4]byte
+	if _, err := io.ReadFull(r, i32[:]); err != nil {
+		return nil, err
+	}
+	h.Version = int32(binary.LittleEndian.Uint32(i32[:]))
+
+	prevHash, err := readFixed(r, 32)
+	if err != nil {
+		return nil, err
+	}
+	if !isZeroHash(prevHash) {
+		c := codec.orDefault()
+		mhb, err := mh.Encode(prevHash, c.Hash)
+		if err != nil {
+			return nil, err
+		}
+		h.Parent = cid.NewCidV1(c.BlockCodec, mhb)
+	}
+
+	if h.MerkleRoot, err = readFixed(r, 32); err != nil {
+		return nil, err
+	}
+	if h.ReservedField, err = readFixed(r, 32); err != nil {
+		return nil, err
+	}
+
+	if _, err := io.ReadFull(r, i32[:]); err != nil {
+		return nil, err
+	}
+	h.Timestamp = binary.LittleEndian.Uint32(i32[:])
+
+	if _, err := io.ReadFull(r, i32[:]); err != nil {
+		return nil, err
+	}
+	h.Bits = binary.LittleEndian.Uint32(i32[:])
+
+	if h.Nonce, err = readFixed(r, 32); err != nil {
+		return nil, err
+	}
+
+	solSize, err := readCount(r)
+	if err != nil {
+		return nil, err
+	}
+	h.SolutionSize = solSize
+	if h.Solution, err = readFixed(r, int(solSize)); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// RawData returns the 1487-byte serialized header, as defined by the Zcash
+// wire format.
+func (h *Header) RawData() []byte {
+	buf := new(bytes.Buffer)
+
+	i32 := make([]byte, 4)
+	binary.LittleEndian.PutUint32(i32, uint32(h.Version))
+	buf.Write(i32)
+
+	if h.Parent != nil {
+		buf.Write(cidToHash(h.Parent))
+	} else {
+		buf.Write(make([]byte, 32))
+	}
+
+	buf.Write(h.MerkleRoot)
+	buf.Write(h.ReservedField)
+
+	binary.LittleEndian.PutUint32(i32, h.Timestamp)
+	buf.Write(i32)
+	binary.LittleEndian.PutUint32(i32, h.Bits)
+	buf.Write(i32)
+
+	buf.Write(h.Nonce)
+
+	writeVarInt(buf, h.SolutionSize)
+	buf.Write(h.Solution)
+
+	return buf.Bytes()
+}
+
+func (h *Header) Cid() *cid.Cid {
+	c := h.codec.orDefault()
+	hsh, _ := mh.Sum(h.RawData(), c.Hash, -1)
+	return cid.NewCidV1(c.BlockCodec, hsh)
+}
+
+func (h *Header) ZecSha() []byte {
+	c := h.codec.orDefault()
+	hsh, _ := mh.Sum(h.RawData(), c.Hash, -1)
+	return []byte(hsh[2:])
+}
+
+func (h *Header) HexHash() string {
+	return fmt.Sprintf("%x", revString(h.ZecSha()))
+}
+
+func (h *Header) Links() []*node.Link {
+	if h.Parent == nil {
+		return nil
+	}
+	return []*node.Link{{Name: "parent", Cid: h.Parent}}
+}
+
+func (h *Header) Loggable() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "zcashHeader",
+	}
+}
+
+func (h *Header) Resolve(path []string) (interface{}, []string, error) {
+	switch path[0] {
+	case "version":
+		return h.Version, path[1:], nil
+	case "parent":
+		if h.Parent == nil {
+			return nil, nil, fmt.Errorf("no such link")
+		}
+		return &node.Link{Name: "parent", Cid: h.Parent}, path[1:], nil
+	case "merkleRoot":
+		return h.MerkleRoot, path[1:], nil
+	case "reserved":
+		return h.ReservedField, path[1:], nil
+	case "timestamp":
+		return h.Timestamp, path[1:], nil
+	case "bits":
+		return h.Bits, path[1:], nil
+	case "nonce":
+		return h.Nonce, path[1:], nil
+	case "solutionSize":
+		return h.SolutionSize, path[1:], nil
+	case "solution":
+		return h.Solution, path[1:], nil
+	default:
+		return nil, nil, fmt.Errorf("no such link")
+	}
+}
+
+func (h *Header) ResolveLink(path []string) (*node.Link, []string, error) {
+	i, rest, err := h.Resolve(path)
+	if err != nil {
+		return nil, rest, err
+	}
+
+	lnk, ok := i.(*node.Link)
+	if !ok {
+		return nil, nil, fmt.Errorf("value was not a link")
+	}
+
+	return lnk, rest, nil
+}
+
+func (h *Header) Size() (uint64, error) {
+	return uint64(len(h.RawData())), nil
+}
+
+func (h *Header) Stat() (*node.NodeStat, error) {
+	return &node.NodeStat{}, nil
+}
+
+func (h *Header) Copy() node.Node {
+	nh := *h
+	return &nh
+}
+
+func (h *Header) String() string {
+	return fmt.Sprintf("zcash header")
+}
+
+func (h *Header) Tree(p string, depth int) []string {
+	if depth == 0 {
+		return nil
+	}
+
+	if p != "" {
+		return nil
+	}
+
+	out := []string{"version", "merkleRoot", "reserved", "timestamp", "bits", "nonce", "solutionSize", "solution"}
+	if h.Parent != nil {
+		out = append(out, "parent")
+	}
+	return out
+}
+
+var _ node.Node = (*Header)(nil)
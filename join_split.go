@@ -0,0 +1,131 @@
+package ipldzec
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Sprout JoinSplit proof sizes: versions 2 and 3 use the PHGR13 proof
+// system, version 4 (Sapling) switches JoinSplits over to Groth16.
+const (
+	phgr13ProofSize  = 296
+	groth16ProofSize = 192
+	jsCiphertextSize = 601
+)
+
+// JSDescription is a single Sprout JoinSplit description, as found in the
+// vJoinSplit array of a Zcash transaction.
+type JSDescription struct {
+	VpubOld      uint64   `json:"vpubOld"`
+	VpubNew      uint64   `json:"vpubNew"`
+	Anchor       []byte   `json:"anchor"`
+	Nullifiers   [][]byte `json:"nullifiers"`
+	Commitments  [][]byte `json:"commitments"`
+	EphemeralKey []byte   `json:"ephemeralKey"`
+	RandomSeed   []byte   `json:"randomSeed"`
+	Macs         [][]byte `json:"macs"`
+	ZKProof      []byte   `json:"zkproof"`
+	Ciphertexts  [][]byte `json:"ciphertexts"`
+}
+
+func (js *JSDescription) encodeTo(w io.Writer) error {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, js.VpubOld)
+	w.Write(buf)
+	binary.LittleEndian.PutUint64(buf, js.VpubNew)
+	w.Write(buf)
+
+	w.Write(js.Anchor)
+	for _, n := range js.Nullifiers {
+		w.Write(n)
+	}
+	for _, c := range js.Commitments {
+		w.Write(c)
+	}
+	w.Write(js.EphemeralKey)
+	w.Write(js.RandomSeed)
+	for _, m := range js.Macs {
+		w.Write(m)
+	}
+	w.Write(js.ZKProof)
+	for _, c := range js.Ciphertexts {
+		w.Write(c)
+	}
+	return nil
+}
+
+// readJSDescription parses a single JoinSplit description from r. txVersion
+// selects the proof system: versions 2 and 3 use PHGR13, version 4 and up
+// use Groth16.
+func readJSDescription(r io.Reader, txVersion uint32) (*JSDescription, error) {
+	js := &JSDescription{}
+
+	buf := make([]byte, 8)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	js.VpubOld = binary.LittleEndian.Uint64(buf)
+
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	js.VpubNew = binary.LittleEndian.Uint64(buf)
+
+	var err error
+	if js.Anchor, err = readFixed(r, 32); err != nil {
+		return nil, err
+	}
+
+	js.Nullifiers = make([][]byte, 2)
+	for i := range js.Nullifiers {
+		if js.Nullifiers[i], err = readFixed(r, 32); err != nil {
+			return nil, err
+		}
+	}
+
+	js.Commitments = make([][]byte, 2)
+	for i := range js.Commitments {
+		if js.Commitments[i], err = readFixed(r, 32); err != nil {
+			return nil, err
+		}
+	}
+
+	if js.EphemeralKey, err = readFixed(r, 32); err != nil {
+		return nil, err
+	}
+	if js.RandomSeed, err = readFixed(r, 32); err != nil {
+		return nil, err
+	}
+
+	js.Macs = make([][]byte, 2)
+	for i := range js.Macs {
+		if js.Macs[i], err = readFixed(r, 32); err != nil {
+			return nil, err
+		}
+	}
+
+	proofSize := phgr13ProofSize
+	if txVersion >= 4 {
+		proofSize = groth16ProofSize
+	}
+	if js.ZKProof, err = readFixed(r, proofSize); err != nil {
+		return nil, err
+	}
+
+	js.Ciphertexts = make([][]byte, 2)
+	for i := range js.Ciphertexts {
+		if js.Ciphertexts[i], err = readFixed(r, jsCiphertextSize); err != nil {
+			return nil, err
+		}
+	}
+
+	return js, nil
+}
+
+func readFixed(r io.Reader, n int) ([]byte, error) {
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
@@ -0,0 +1,200 @@
+package ipldzec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	cid "github.com/ipfs/go-cid"
+	node "github.com/ipfs/go-ipld-format"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// MerkleProof is a Bitcoin/Zcash-style Merkle inclusion proof: the sibling
+// hash encountered at each level of the tree on the path from a
+// transaction's leaf up to the root, together with a bit recording which
+// side of the pairing the sibling falls on.
+type MerkleProof struct {
+	TxIndex int      `json:"txIndex"`
+	Hashes  [][]byte `json:"hashes"`
+	// Left[i] is true when Hashes[i] is the left sibling at that level.
+	Left []bool `json:"left"`
+}
+
+func doubleSha256(b []byte) []byte {
+	sum, _ := mh.Sum(b, mh.DBL_SHA2_256, -1)
+	return []byte(sum[2:])
+}
+
+// MerkleProof builds an inclusion proof for the transaction at txIndex
+// against the block's transaction list, using the same duplicate-last-node
+// convention as Bitcoin's Merkle tree.
+func (b *Block) MerkleProof(txIndex int) (*MerkleProof, error) {
+	if txIndex < 0 || txIndex >= len(b.Txs) {
+		return nil, fmt.Errorf("tx index out of range")
+	}
+
+	level := make([][]byte, len(b.Txs))
+	for i, tx := range b.Txs {
+		level[i] = tx.ZecSha()
+	}
+
+	proof := &MerkleProof{TxIndex: txIndex}
+	idx := txIndex
+
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+
+		sibling := idx ^ 1
+		proof.Hashes = append(proof.Hashes, level[sibling])
+		proof.Left = append(proof.Left, sibling < idx)
+
+		next := make([][]byte, len(level)/2)
+		for j := range next {
+			next[j] = doubleSha256(append(append([]byte{}, level[2*j]...), level[2*j+1]...))
+		}
+		level = next
+		idx /= 2
+	}
+
+	return proof, nil
+}
+
+// VerifyMerkleProof recomputes the Merkle root implied by txid and proof,
+// and reports whether it matches root. root and txid are expected in the
+// same internal (non-reversed) byte order as Header.MerkleRoot and
+// Tx.ZecSha().
+func VerifyMerkleProof(root []byte, txid []byte, proof *MerkleProof) bool {
+	cur := txid
+	for i, sib := range proof.Hashes {
+		if proof.Left[i] {
+			cur = doubleSha256(append(append([]byte{}, sib...), cur...))
+		} else {
+			cur = doubleSha256(append(append([]byte{}, cur...), sib...))
+		}
+	}
+	return bytes.Equal(cur, root)
+}
+
+func (p *MerkleProof) RawData() []byte {
+	buf := new(bytes.Buffer)
+	idx := make([]byte, 4)
+	binary.LittleEndian.PutUint32(idx, uint32(p.TxIndex))
+	buf.Write(idx)
+
+	writeVarInt(buf, uint64(len(p.Hashes)))
+	for i, h := range p.Hashes {
+		buf.Write(h)
+		if p.Left[i] {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+func DecodeMerkleProof(r io.Reader) (*MerkleProof, error) {
+	idxBuf, err := readFixed(r, 4)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := readCount(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &MerkleProof{TxIndex: int(binary.LittleEndian.Uint32(idxBuf))}
+	for i := uint64(0); i < n; i++ {
+		h, err := readFixed(r, 32)
+		if err != nil {
+			return nil, err
+		}
+		bit, err := readFixed(r, 1)
+		if err != nil {
+			return nil, err
+		}
+		p.Hashes = append(p.Hashes, h)
+		p.Left = append(p.Left, bit[0] == 1)
+	}
+
+	return p, nil
+}
+
+func (p *MerkleProof) Cid() *cid.Cid {
+	h, _ := mh.Sum(p.RawData(), mh.DBL_SHA2_256, -1)
+	return cid.NewCidV1(cid.Raw, h)
+}
+
+func (p *MerkleProof) Links() []*node.Link {
+	return nil
+}
+
+func (p *MerkleProof) Loggable() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "zcashMerkleProof",
+	}
+}
+
+func (p *MerkleProof) Resolve(path []string) (interface{}, []string, error) {
+	switch path[0] {
+	case "txIndex":
+		return p.TxIndex, path[1:], nil
+	case "hashes":
+		return p.Hashes, path[1:], nil
+	case "left":
+		return p.Left, path[1:], nil
+	default:
+		return nil, nil, fmt.Errorf("no such link")
+	}
+}
+
+func (p *MerkleProof) ResolveLink(path []string) (*node.Link, []string, error) {
+	i, rest, err := p.Resolve(path)
+	if err != nil {
+		return nil, rest, err
+	}
+
+	lnk, ok := i.(*node.Link)
+	if !ok {
+		return nil, nil, fmt.Errorf("value was not a link")
+	}
+
+	return lnk, rest, nil
+}
+
+func (p *MerkleProof) Size() (uint64, error) {
+	return uint64(len(p.RawData())), nil
+}
+
+func (p *MerkleProof) Stat() (*node.NodeStat, error) {
+	return &node.NodeStat{}, nil
+}
+
+func (p *MerkleProof) Copy() node.Node {
+	np := *p
+	return &np
+}
+
+func (p *MerkleProof) String() string {
+	return fmt.Sprintf("zcash merkle proof")
+}
+
+func (p *MerkleProof) Tree(path string, depth int) []string {
+	if depth == 0 {
+		return nil
+	}
+
+	if path != "" {
+		return nil
+	}
+
+	return []string{"txIndex", "hashes", "left"}
+}
+
+var _ node.Node = (*MerkleProof)(nil)
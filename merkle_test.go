@@ -0,0 +1,85 @@
+package ipldzec
+
+import (
+	"bytes"
+	"testing"
+)
+
+func fixtureBlockWithTxs(n int) *Block {
+	b := &Block{Header: fixtureHeader()}
+	for i := 0; i < n; i++ {
+		tx := fixtureSaplingTx()
+		tx.LockTime = uint32(i)
+		b.Txs = append(b.Txs, tx)
+	}
+	return b
+}
+
+func merkleRoot(b *Block) []byte {
+	level := make([][]byte, len(b.Txs))
+	for i, tx := range b.Txs {
+		level[i] = tx.ZecSha()
+	}
+
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		next := make([][]byte, len(level)/2)
+		for j := range next {
+			next[j] = doubleSha256(append(append([]byte{}, level[2*j]...), level[2*j+1]...))
+		}
+		level = next
+	}
+	return level[0]
+}
+
+func TestMerkleProofVerify(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 5, 8} {
+		b := fixtureBlockWithTxs(n)
+		root := merkleRoot(b)
+
+		for i, tx := range b.Txs {
+			proof, err := b.MerkleProof(i)
+			if err != nil {
+				t.Fatalf("n=%d MerkleProof(%d): %v", n, i, err)
+			}
+
+			if !VerifyMerkleProof(root, tx.ZecSha(), proof) {
+				t.Fatalf("n=%d proof for tx %d did not verify", n, i)
+			}
+		}
+	}
+}
+
+func TestMerkleProofRejectsWrongTx(t *testing.T) {
+	b := fixtureBlockWithTxs(4)
+	root := merkleRoot(b)
+
+	proof, err := b.MerkleProof(0)
+	if err != nil {
+		t.Fatalf("MerkleProof: %v", err)
+	}
+
+	if VerifyMerkleProof(root, b.Txs[1].ZecSha(), proof) {
+		t.Fatalf("proof for tx 0 should not verify against tx 1's hash")
+	}
+}
+
+func TestMerkleProofEncodeDecodeRoundTrip(t *testing.T) {
+	b := fixtureBlockWithTxs(3)
+	proof, err := b.MerkleProof(1)
+	if err != nil {
+		t.Fatalf("MerkleProof: %v", err)
+	}
+
+	raw := proof.RawData()
+	got, err := DecodeMerkleProof(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("DecodeMerkleProof: %v", err)
+	}
+
+	if !bytes.Equal(got.RawData(), raw) {
+		t.Fatalf("decoded proof re-encodes differently")
+	}
+}
@@ -0,0 +1,100 @@
+package ipldzec
+
+import "io"
+
+const (
+	saplingSpendProofSize  = 192
+	saplingOutputProofSize = 192
+	saplingEncCiphertext   = 580
+	saplingOutCiphertext   = 80
+)
+
+// SpendDescription is a single Sapling shielded spend, as found in the
+// vShieldedSpend array of a v4+ Zcash transaction.
+type SpendDescription struct {
+	CV           []byte `json:"cv"`
+	Anchor       []byte `json:"anchor"`
+	Nullifier    []byte `json:"nullifier"`
+	RK           []byte `json:"rk"`
+	ZKProof      []byte `json:"zkproof"`
+	SpendAuthSig []byte `json:"spendAuthSig"`
+}
+
+func (s *SpendDescription) encodeTo(w io.Writer) error {
+	w.Write(s.CV)
+	w.Write(s.Anchor)
+	w.Write(s.Nullifier)
+	w.Write(s.RK)
+	w.Write(s.ZKProof)
+	w.Write(s.SpendAuthSig)
+	return nil
+}
+
+func readSpendDescription(r io.Reader) (*SpendDescription, error) {
+	s := &SpendDescription{}
+	var err error
+	if s.CV, err = readFixed(r, 32); err != nil {
+		return nil, err
+	}
+	if s.Anchor, err = readFixed(r, 32); err != nil {
+		return nil, err
+	}
+	if s.Nullifier, err = readFixed(r, 32); err != nil {
+		return nil, err
+	}
+	if s.RK, err = readFixed(r, 32); err != nil {
+		return nil, err
+	}
+	if s.ZKProof, err = readFixed(r, saplingSpendProofSize); err != nil {
+		return nil, err
+	}
+	if s.SpendAuthSig, err = readFixed(r, 64); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// OutputDescription is a single Sapling shielded output, as found in the
+// vShieldedOutput array of a v4+ Zcash transaction.
+type OutputDescription struct {
+	CV            []byte `json:"cv"`
+	Cmu           []byte `json:"cmu"`
+	EphemeralKey  []byte `json:"ephemeralKey"`
+	EncCiphertext []byte `json:"encCiphertext"`
+	OutCiphertext []byte `json:"outCiphertext"`
+	ZKProof       []byte `json:"zkproof"`
+}
+
+func (o *OutputDescription) encodeTo(w io.Writer) error {
+	w.Write(o.CV)
+	w.Write(o.Cmu)
+	w.Write(o.EphemeralKey)
+	w.Write(o.EncCiphertext)
+	w.Write(o.OutCiphertext)
+	w.Write(o.ZKProof)
+	return nil
+}
+
+func readOutputDescription(r io.Reader) (*OutputDescription, error) {
+	o := &OutputDescription{}
+	var err error
+	if o.CV, err = readFixed(r, 32); err != nil {
+		return nil, err
+	}
+	if o.Cmu, err = readFixed(r, 32); err != nil {
+		return nil, err
+	}
+	if o.EphemeralKey, err = readFixed(r, 32); err != nil {
+		return nil, err
+	}
+	if o.EncCiphertext, err = readFixed(r, saplingEncCiphertext); err != nil {
+		return nil, err
+	}
+	if o.OutCiphertext, err = readFixed(r, saplingOutCiphertext); err != nil {
+		return nil, err
+	}
+	if o.ZKProof, err = readFixed(r, saplingOutputProofSize); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
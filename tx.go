@@ -7,25 +7,100 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"sync"
 
 	cid "github.com/ipfs/go-cid"
 	node "github.com/ipfs/go-ipld-format"
 	mh "github.com/multiformats/go-multihash"
 )
 
+// Version group IDs identifying the Overwinter and Sapling transaction
+// formats, carried in nVersionGroupId when fOverwintered is set.
+const (
+	overwinterVersionGroupID = 0x03c48270
+	saplingVersionGroupID    = 0x892f2085
+)
+
 type Tx struct {
-	Version    uint32           `json:"version"`
-	Inputs     []*TxIn          `json:"inputs"`
-	Outputs    []*TxOut         `json:"outputs"`
-	LockTime   uint32           `json:"locktime"`
+	Overwintered   bool   `json:"fOverwintered,omitempty"`
+	Version        uint32 `json:"version"`
+	VersionGroupID uint32 `json:"nVersionGroupId,omitempty"`
+
+	Inputs   []*TxIn  `json:"inputs"`
+	Outputs  []*TxOut `json:"outputs"`
+	LockTime uint32   `json:"locktime"`
+
+	// ExpiryHeight is present from Overwinter (v3) onward.
+	ExpiryHeight uint32 `json:"nExpiryHeight,omitempty"`
+
+	// ValueBalance and the shielded spend/output arrays are present from
+	// Sapling (v4) onward.
+	ValueBalance    int64                `json:"valueBalance,omitempty"`
+	ShieldedSpends  []*SpendDescription  `json:"shieldedSpends,omitempty"`
+	ShieldedOutputs []*OutputDescription `json:"shieldedOutputs,omitempty"`
+
 	JoinSplits []*JSDescription `json:"joinSplits,omitempty"`
 	JSPubKey   []byte           `json:"jsPubKey,omitempty"`
 	JSSig      []byte           `json:"jsSig,omitempty"`
+
+	// BindingSig authorizes the Sapling value balance and is present
+	// whenever there is at least one shielded spend or output.
+	BindingSig []byte `json:"bindingSig,omitempty"`
+
+	// cacheMu guards rawCache and cidCache, which memoize RawData/Cid
+	// across repeated calls. node.Node is documented as thread-safe, and
+	// bulk block ingestion is exactly the workload where the same *Tx
+	// gets fanned out across goroutines, so the cache itself must be
+	// safe for concurrent use even though a Tx's other fields are not
+	// safe to mutate concurrently. The cache goes stale on mutation, so
+	// callers that mutate a Tx in place must call Invalidate() afterwards.
+	cacheMu  sync.Mutex
+	rawCache []byte
+	cidCache *cid.Cid
+
+	// codec selects the CID codec/multihash t is addressed with. The
+	// zero value falls back to DefaultZcashCodec.
+	codec Codec
+}
+
+// NewTx returns an empty Tx addressed under codec instead of
+// DefaultZcashCodec. Use the zero Codec{} to get the default behavior.
+func NewTx(codec Codec) *Tx {
+	return &Tx{codec: codec}
+}
+
+// txBufPool reuses the scratch buffers RawData needs to serialize a Tx,
+// avoiding a fresh allocation on every call during bulk block ingestion.
+var txBufPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// Invalidate clears the cached RawData/Cid for t. Call it after mutating
+// a Tx that was previously serialized or hashed.
+func (t *Tx) Invalidate() {
+	t.cacheMu.Lock()
+	t.rawCache = nil
+	t.cidCache = nil
+	t.cacheMu.Unlock()
 }
 
 func (t *Tx) Cid() *cid.Cid {
-	h, _ := mh.Sum(t.RawData(), mh.DBL_SHA2_256, -1)
-	return cid.NewCidV1(cid.ZcashTx, h)
+	t.cacheMu.Lock()
+	if t.cidCache != nil {
+		c := t.cidCache
+		t.cacheMu.Unlock()
+		return c
+	}
+	t.cacheMu.Unlock()
+
+	c := t.codec.orDefault()
+	h, _ := mh.Sum(t.RawData(), c.Hash, -1)
+	cc := cid.NewCidV1(c.TxCodec, h)
+
+	t.cacheMu.Lock()
+	t.cidCache = cc
+	t.cacheMu.Unlock()
+	return cc
 }
 
 func (t *Tx) Links() []*node.Link {
@@ -40,36 +115,238 @@ func (t *Tx) Links() []*node.Link {
 	return out
 }
 
+// RawData returns the serialized Zcash wire format of t, memoized until
+// the next Invalidate(). Encoding itself runs through WriteTo via a
+// pooled buffer, so repeated calls after the first only pay for the
+// cache hit.
 func (t *Tx) RawData() []byte {
-	buf := new(bytes.Buffer)
-	i := make([]byte, 4)
-	binary.LittleEndian.PutUint32(i, t.Version)
-	buf.Write(i)
-	writeVarInt(buf, uint64(len(t.Inputs)))
+	t.cacheMu.Lock()
+	if t.rawCache != nil {
+		out := t.rawCache
+		t.cacheMu.Unlock()
+		return out
+	}
+	t.cacheMu.Unlock()
+
+	buf := txBufPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	t.WriteTo(buf)
+
+	out := make([]byte, buf.Len())
+	copy(out, buf.Bytes())
+	txBufPool.Put(buf)
+
+	t.cacheMu.Lock()
+	t.rawCache = out
+	t.cacheMu.Unlock()
+	return out
+}
+
+// WriteTo streams the serialized Zcash wire format of t to w without
+// buffering the whole transaction in memory, following the
+// memory-pressure work in the LBRY lbcd fork. It is the encoder RawData
+// and Cid ultimately build on.
+func (t *Tx) WriteTo(w io.Writer) (int64, error) {
+	ew := &errWriter{w: w}
+	var i [4]byte
+
+	header := t.Version
+	if t.Overwintered {
+		header |= 1 << 31
+	}
+	binary.LittleEndian.PutUint32(i[:], header)
+	ew.Write(i[:])
+	if t.Overwintered {
+		binary.LittleEndian.PutUint32(i[:], t.VersionGroupID)
+		ew.Write(i[:])
+	}
+
+	writeVarInt(ew, uint64(len(t.Inputs)))
 	for _, inp := range t.Inputs {
-		inp.WriteTo(buf)
+		inp.encodeTo(ew)
 	}
 
-	writeVarInt(buf, uint64(len(t.Outputs)))
+	writeVarInt(ew, uint64(len(t.Outputs)))
 	for _, out := range t.Outputs {
-		out.WriteTo(buf)
+		out.encodeTo(ew)
 	}
 
-	binary.LittleEndian.PutUint32(i, t.LockTime)
-	buf.Write(i)
+	binary.LittleEndian.PutUint32(i[:], t.LockTime)
+	ew.Write(i[:])
 	if t.Version == 1 {
-		return buf.Bytes()
+		return ew.n, ew.err
+	}
+
+	if t.Overwintered {
+		binary.LittleEndian.PutUint32(i[:], t.ExpiryHeight)
+		ew.Write(i[:])
+	}
+
+	if t.Version >= 4 {
+		var vb [8]byte
+		binary.LittleEndian.PutUint64(vb[:], uint64(t.ValueBalance))
+		ew.Write(vb[:])
+
+		writeVarInt(ew, uint64(len(t.ShieldedSpends)))
+		for _, sp := range t.ShieldedSpends {
+			sp.encodeTo(ew)
+		}
+
+		writeVarInt(ew, uint64(len(t.ShieldedOutputs)))
+		for _, out := range t.ShieldedOutputs {
+			out.encodeTo(ew)
+		}
 	}
 
-	writeVarInt(buf, uint64(len(t.JoinSplits)))
+	writeVarInt(ew, uint64(len(t.JoinSplits)))
 	for _, js := range t.JoinSplits {
-		js.WriteTo(buf)
+		js.encodeTo(ew)
+	}
+
+	if len(t.JoinSplits) > 0 {
+		ew.Write(t.JSPubKey)
+		ew.Write(t.JSSig)
+	}
+
+	if t.Version >= 4 && (len(t.ShieldedSpends) > 0 || len(t.ShieldedOutputs) > 0) {
+		ew.Write(t.BindingSig)
+	}
+
+	return ew.n, ew.err
+}
+
+// DecodeTx parses the Zcash transaction wire format from r, following the
+// pattern ipld-eth-server uses to wrap wire.MsgTx.Deserialize for Bitcoin.
+// It supports the legacy v1/v2 format as well as Overwinter (v3) and
+// Sapling (v4) transactions.
+func DecodeTx(r io.Reader) (*Tx, error) {
+	return DecodeTxWithCodec(r, DefaultZcashCodec)
+}
+
+// DecodeTxWithCodec is DecodeTx, but addresses the resulting Tx (and any
+// previous-tx links parsed from its inputs) under codec instead of
+// DefaultZcashCodec.
+func DecodeTxWithCodec(r io.Reader, codec Codec) (*Tx, error) {
+	var buf [4]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return nil, err
+	}
+	header := binary.LittleEndian.Uint32(buf[:])
+
+	t := &Tx{
+		Overwintered: header>>31 == 1,
+		Version:      header &^ (1 << 31),
+		codec:        codec,
+	}
+
+	if t.Overwintered {
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return nil, err
+		}
+		t.VersionGroupID = binary.LittleEndian.Uint32(buf[:])
+	}
+
+	nIn, err := readCount(r)
+	if err != nil {
+		return nil, err
+	}
+	t.Inputs = make([]*TxIn, nIn)
+	for i := range t.Inputs {
+		if t.Inputs[i], err = readTxIn(r, codec); err != nil {
+			return nil, err
+		}
+	}
+
+	nOut, err := readCount(r)
+	if err != nil {
+		return nil, err
+	}
+	t.Outputs = make([]*TxOut, nOut)
+	for i := range t.Outputs {
+		if t.Outputs[i], err = readTxOut(r); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return nil, err
 	}
+	t.LockTime = binary.LittleEndian.Uint32(buf[:])
 
-	buf.Write(t.JSPubKey)
-	buf.Write(t.JSSig)
+	if t.Version == 1 {
+		return t, nil
+	}
+
+	if t.Overwintered {
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return nil, err
+		}
+		t.ExpiryHeight = binary.LittleEndian.Uint32(buf[:])
+	}
+
+	if t.Version >= 4 {
+		var vb [8]byte
+		if _, err := io.ReadFull(r, vb[:]); err != nil {
+			return nil, err
+		}
+		t.ValueBalance = int64(binary.LittleEndian.Uint64(vb[:]))
+
+		nSpends, err := readCount(r)
+		if err != nil {
+			return nil, err
+		}
+		t.ShieldedSpends = make([]*SpendDescription, nSpends)
+		for i := range t.ShieldedSpends {
+			if t.ShieldedSpends[i], err = readSpendDescription(r); err != nil {
+				return nil, err
+			}
+		}
 
-	return buf.Bytes()
+		nOutputs, err := readCount(r)
+		if err != nil {
+			return nil, err
+		}
+		t.ShieldedOutputs = make([]*OutputDescription, nOutputs)
+		for i := range t.ShieldedOutputs {
+			if t.ShieldedOutputs[i], err = readOutputDescription(r); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	nJoinSplits, err := readCount(r)
+	if err != nil {
+		return nil, err
+	}
+	t.JoinSplits = make([]*JSDescription, nJoinSplits)
+	for i := range t.JoinSplits {
+		if t.JoinSplits[i], err = readJSDescription(r, t.Version); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(t.JoinSplits) > 0 {
+		if t.JSPubKey, err = readFixed(r, 32); err != nil {
+			return nil, err
+		}
+		if t.JSSig, err = readFixed(r, 64); err != nil {
+			return nil, err
+		}
+	}
+
+	if t.Version >= 4 && (len(t.ShieldedSpends) > 0 || len(t.ShieldedOutputs) > 0) {
+		if t.BindingSig, err = readFixed(r, 64); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// DecodeTxBytes is a convenience wrapper around DecodeTx for callers that
+// already have the full transaction in memory.
+func DecodeTxBytes(b []byte) (*Tx, error) {
+	return DecodeTx(bytes.NewReader(b))
 }
 
 func (t *Tx) Loggable() map[string]interface{} {
@@ -143,12 +420,92 @@ func (t *Tx) Resolve(path []string) (interface{}, []string, error) {
 		default:
 			return nil, nil, fmt.Errorf("no such link")
 		}
+	case "fOverwintered":
+		return t.Overwintered, path[1:], nil
+	case "nVersionGroupId":
+		return t.VersionGroupID, path[1:], nil
+	case "nExpiryHeight":
+		return t.ExpiryHeight, path[1:], nil
+	case "valueBalance":
+		return t.ValueBalance, path[1:], nil
+	case "shieldedSpends":
+		if len(path) == 1 {
+			return t.ShieldedSpends, nil, nil
+		}
+
+		index, err := strconv.Atoi(path[1])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if index >= len(t.ShieldedSpends) || index < 0 {
+			return nil, nil, fmt.Errorf("index out of range")
+		}
+
+		sp := t.ShieldedSpends[index]
+		if len(path) == 2 {
+			return sp, nil, nil
+		}
+
+		switch path[2] {
+		case "cv":
+			return sp.CV, path[3:], nil
+		case "anchor":
+			return sp.Anchor, path[3:], nil
+		case "nullifier":
+			return sp.Nullifier, path[3:], nil
+		case "rk":
+			return sp.RK, path[3:], nil
+		case "zkproof":
+			return sp.ZKProof, path[3:], nil
+		case "spendAuthSig":
+			return sp.SpendAuthSig, path[3:], nil
+		default:
+			return nil, nil, fmt.Errorf("no such link")
+		}
+	case "shieldedOutputs":
+		if len(path) == 1 {
+			return t.ShieldedOutputs, nil, nil
+		}
+
+		index, err := strconv.Atoi(path[1])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if index >= len(t.ShieldedOutputs) || index < 0 {
+			return nil, nil, fmt.Errorf("index out of range")
+		}
+
+		out := t.ShieldedOutputs[index]
+		if len(path) == 2 {
+			return out, nil, nil
+		}
+
+		switch path[2] {
+		case "cv":
+			return out.CV, path[3:], nil
+		case "cmu":
+			return out.Cmu, path[3:], nil
+		case "ephemeralKey":
+			return out.EphemeralKey, path[3:], nil
+		case "encCiphertext":
+			return out.EncCiphertext, path[3:], nil
+		case "outCiphertext":
+			return out.OutCiphertext, path[3:], nil
+		case "zkproof":
+			return out.ZKProof, path[3:], nil
+		default:
+			return nil, nil, fmt.Errorf("no such link")
+		}
 	case "joinSplits":
 		return t.JoinSplits, path[1:], nil
 	case "jsPubKey":
 		return t.JSPubKey, path[1:], nil
 	case "jsSig":
 		return t.JSSig, path[1:], nil
+	case "bindingSig":
+		return t.BindingSig, path[1:], nil
 	default:
 		return nil, nil, fmt.Errorf("no such link")
 	}
@@ -177,8 +534,32 @@ func (t *Tx) Stat() (*node.NodeStat, error) {
 }
 
 func (t *Tx) Copy() node.Node {
-	nt := *t // cheating shallow copy
-	return &nt
+	// Field-by-field, not "nt := *t": t carries a cacheMu Mutex, and
+	// copying a struct through a Mutex trips go vet's lock-copy check.
+	nt := &Tx{
+		Overwintered:    t.Overwintered,
+		Version:         t.Version,
+		VersionGroupID:  t.VersionGroupID,
+		Inputs:          t.Inputs,
+		Outputs:         t.Outputs,
+		LockTime:        t.LockTime,
+		ExpiryHeight:    t.ExpiryHeight,
+		ValueBalance:    t.ValueBalance,
+		ShieldedSpends:  t.ShieldedSpends,
+		ShieldedOutputs: t.ShieldedOutputs,
+		JoinSplits:      t.JoinSplits,
+		JSPubKey:        t.JSPubKey,
+		JSSig:           t.JSSig,
+		BindingSig:      t.BindingSig,
+		codec:           t.codec,
+	}
+
+	t.cacheMu.Lock()
+	nt.rawCache = t.rawCache
+	nt.cidCache = t.cidCache
+	t.cacheMu.Unlock()
+
+	return nt
 }
 
 func (t *Tx) String() string {
@@ -195,16 +576,56 @@ func (t *Tx) Tree(p string, depth int) []string {
 		return t.treeInputs(nil, depth+1)
 	case "outputs":
 		return t.treeOutputs(nil, depth+1)
+	case "shieldedSpends":
+		return t.treeShieldedSpends(nil, depth+1)
+	case "shieldedOutputs":
+		return t.treeShieldedOutputs(nil, depth+1)
 	case "":
-		out := []string{"version", "timeLock", "inputs", "outputs", "joinSplits", "jsPubKey", "jsSig"}
+		out := []string{
+			"version", "lockTime", "inputs", "outputs", "joinSplits", "jsPubKey", "jsSig",
+			"fOverwintered", "nVersionGroupId", "nExpiryHeight", "valueBalance",
+			"shieldedSpends", "shieldedOutputs", "bindingSig",
+		}
 		out = t.treeInputs(out, depth)
 		out = t.treeOutputs(out, depth)
+		out = t.treeShieldedSpends(out, depth)
+		out = t.treeShieldedOutputs(out, depth)
 		return out
 	default:
 		return nil
 	}
 }
 
+func (t *Tx) treeShieldedSpends(out []string, depth int) []string {
+	if depth < 2 {
+		return out
+	}
+
+	for i := range t.ShieldedSpends {
+		sp := "shieldedSpends/" + fmt.Sprint(i)
+		out = append(out, sp)
+		if depth > 2 {
+			out = append(out, sp+"/cv", sp+"/anchor", sp+"/nullifier", sp+"/rk", sp+"/zkproof", sp+"/spendAuthSig")
+		}
+	}
+	return out
+}
+
+func (t *Tx) treeShieldedOutputs(out []string, depth int) []string {
+	if depth < 2 {
+		return out
+	}
+
+	for i := range t.ShieldedOutputs {
+		o := "shieldedOutputs/" + fmt.Sprint(i)
+		out = append(out, o)
+		if depth > 2 {
+			out = append(out, o+"/cv", o+"/cmu", o+"/ephemeralKey", o+"/encCiphertext", o+"/outCiphertext", o+"/zkproof")
+		}
+	}
+	return out
+}
+
 func (t *Tx) treeInputs(out []string, depth int) []string {
 	if depth < 2 {
 		return out
@@ -236,8 +657,9 @@ func (t *Tx) treeOutputs(out []string, depth int) []string {
 }
 
 func (t *Tx) ZecSha() []byte {
-	mh, _ := mh.Sum(t.RawData(), mh.DBL_SHA2_256, -1)
-	return []byte(mh[2:])
+	c := t.codec.orDefault()
+	sum, _ := mh.Sum(t.RawData(), c.Hash, -1)
+	return []byte(sum[2:])
 }
 
 func (t *Tx) HexHash() string {
@@ -245,9 +667,13 @@ func (t *Tx) HexHash() string {
 }
 
 func txHashToLink(b []byte) *node.Link {
-	mhb, _ := mh.Encode(b, mh.DBL_SHA2_256)
-	c := cid.NewCidV1(cid.ZcashTx, mhb)
-	return &node.Link{Cid: c}
+	return txHashToLinkWithCodec(b, DefaultZcashCodec)
+}
+
+func txHashToLinkWithCodec(b []byte, codec Codec) *node.Link {
+	c := codec.orDefault()
+	mhb, _ := mh.Encode(b, c.Hash)
+	return &node.Link{Cid: cid.NewCidV1(c.TxCodec, mhb)}
 }
 
 type TxIn struct {
@@ -257,13 +683,13 @@ type TxIn struct {
 	SeqNo       uint32   `json:"sequence"`
 }
 
-func (i *TxIn) WriteTo(w io.Writer) error {
-	buf := make([]byte, 36)
+func (i *TxIn) encodeTo(w io.Writer) error {
+	var buf [36]byte
 	if i.PrevTx != nil {
 		copy(buf[:32], cidToHash(i.PrevTx))
 	}
 	binary.LittleEndian.PutUint32(buf[32:36], i.PrevTxIndex)
-	w.Write(buf)
+	w.Write(buf[:])
 
 	writeVarInt(w, uint64(len(i.Script)))
 	w.Write(i.Script)
@@ -272,12 +698,65 @@ func (i *TxIn) WriteTo(w io.Writer) error {
 	return nil
 }
 
+func readTxIn(r io.Reader, codec Codec) (*TxIn, error) {
+	hash, err := readFixed(r, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	voutBuf, err := readFixed(r, 4)
+	if err != nil {
+		return nil, err
+	}
+
+	scriptLen, err := readCount(r)
+	if err != nil {
+		return nil, err
+	}
+
+	script, err := readFixed(r, int(scriptLen))
+	if err != nil {
+		return nil, err
+	}
+
+	seqBuf, err := readFixed(r, 4)
+	if err != nil {
+		return nil, err
+	}
+
+	in := &TxIn{
+		PrevTxIndex: binary.LittleEndian.Uint32(voutBuf),
+		Script:      script,
+		SeqNo:       binary.LittleEndian.Uint32(seqBuf),
+	}
+
+	if !isZeroHash(hash) {
+		c := codec.orDefault()
+		mhb, err := mh.Encode(hash, c.Hash)
+		if err != nil {
+			return nil, err
+		}
+		in.PrevTx = cid.NewCidV1(c.TxCodec, mhb)
+	}
+
+	return in, nil
+}
+
+func isZeroHash(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
 type TxOut struct {
 	Value  uint64 `json:"value"`
 	Script []byte `json:"script"`
 }
 
-func (o *TxOut) WriteTo(w io.Writer) error {
+func (o *TxOut) encodeTo(w io.Writer) error {
 	val := make([]byte, 8)
 	binary.LittleEndian.PutUint64(val, o.Value)
 	w.Write(val)
@@ -286,4 +765,26 @@ func (o *TxOut) WriteTo(w io.Writer) error {
 	return nil
 }
 
+func readTxOut(r io.Reader) (*TxOut, error) {
+	valBuf, err := readFixed(r, 8)
+	if err != nil {
+		return nil, err
+	}
+
+	scriptLen, err := readCount(r)
+	if err != nil {
+		return nil, err
+	}
+
+	script, err := readFixed(r, int(scriptLen))
+	if err != nil {
+		return nil, err
+	}
+
+	return &TxOut{
+		Value:  binary.LittleEndian.Uint64(valBuf),
+		Script: script,
+	}, nil
+}
+
 var _ node.Node = (*Tx)(nil)
@@ -0,0 +1,44 @@
+package ipldzec
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func sampleTx() *Tx {
+	return &Tx{
+		Version: 2,
+		Inputs: []*TxIn{
+			{PrevTxIndex: 0, Script: []byte{0x01, 0x02, 0x03}, SeqNo: 0xffffffff},
+		},
+		Outputs: []*TxOut{
+			{Value: 5000000000, Script: []byte{0x76, 0xa9, 0x14}},
+		},
+		LockTime: 0,
+	}
+}
+
+func BenchmarkTxRawData(b *testing.B) {
+	tx := sampleTx()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		tx.Invalidate()
+		_ = tx.RawData()
+	}
+}
+
+func BenchmarkTxRawDataCached(b *testing.B) {
+	tx := sampleTx()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = tx.RawData()
+	}
+}
+
+func BenchmarkTxWriteTo(b *testing.B) {
+	tx := sampleTx()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		tx.WriteTo(ioutil.Discard)
+	}
+}
@@ -0,0 +1,496 @@
+package ipldzec
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	cid "github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// satoshiPerZec is the number of zatoshi in one ZEC.
+const satoshiPerZec = 100000000
+
+func zatToZecString(v uint64) string {
+	return strconv.FormatUint(v/satoshiPerZec, 10) + "." + zeroPad(v%satoshiPerZec)
+}
+
+func signedZatToZecString(v int64) string {
+	if v < 0 {
+		return "-" + zatToZecString(uint64(-v))
+	}
+	return zatToZecString(uint64(v))
+}
+
+func zeroPad(v uint64) string {
+	s := strconv.FormatUint(v, 10)
+	for len(s) < 8 {
+		s = "0" + s
+	}
+	return s
+}
+
+func zecStringToZat(s string) (uint64, error) {
+	parts := strings.SplitN(s, ".", 2)
+	whole := parts[0]
+	var frac string
+	if len(parts) == 2 {
+		frac = parts[1]
+	}
+
+	w, err := strconv.ParseUint(whole, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	for len(frac) < 8 {
+		frac += "0"
+	}
+	frac = frac[:8]
+
+	f, err := strconv.ParseUint(frac, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return w*satoshiPerZec + f, nil
+}
+
+func zecStringToSignedZat(s string) (int64, error) {
+	neg := strings.HasPrefix(s, "-")
+	if neg {
+		s = s[1:]
+	}
+
+	v, err := zecStringToZat(s)
+	if err != nil {
+		return 0, err
+	}
+
+	if neg {
+		return -int64(v), nil
+	}
+	return int64(v), nil
+}
+
+// The JSON types below mirror the shape zcashd's getrawtransaction
+// verbose=1 produces, so a Tx can be marshaled to and decoded from actual
+// RPC output or ecosystem JSON dumps.
+
+type scriptSigJSON struct {
+	Hex string `json:"hex"`
+}
+
+type scriptPubKeyJSON struct {
+	Hex string `json:"hex"`
+}
+
+// coinbaseVinJSON and regularVinJSON are the two mutually exclusive shapes
+// zcashd uses for a transaction input, distinguished by the presence of
+// "coinbase" rather than by zero-valued fields (a coinbase input's real
+// vout is the nonzero 0xffffffff, so omitempty cannot tell them apart). An
+// input is only marshaled in the coinbase shape when it matches zcashd's
+// own IsCoinBase check -- a null previous-tx hash *and* vout == 0xffffffff
+// -- so a zero-hash input with any other index still round-trips via the
+// regular shape instead of silently losing its real vout.
+type coinbaseVinJSON struct {
+	Coinbase string `json:"coinbase"`
+	Sequence uint32 `json:"sequence"`
+}
+
+type regularVinJSON struct {
+	TxID      string        `json:"txid"`
+	Vout      uint32        `json:"vout"`
+	ScriptSig scriptSigJSON `json:"scriptSig"`
+	Sequence  uint32        `json:"sequence"`
+}
+
+type txOutJSON struct {
+	Value        string           `json:"value"`
+	ValueZat     uint64           `json:"valueZat"`
+	N            int              `json:"n"`
+	ScriptPubKey scriptPubKeyJSON `json:"scriptPubKey"`
+}
+
+type spendDescriptionJSON struct {
+	CV           string `json:"cv"`
+	Anchor       string `json:"anchor"`
+	Nullifier    string `json:"nullifier"`
+	RK           string `json:"rk"`
+	Proof        string `json:"proof"`
+	SpendAuthSig string `json:"spendAuthSig"`
+}
+
+type outputDescriptionJSON struct {
+	CV            string `json:"cv"`
+	Cmu           string `json:"cmu"`
+	EphemeralKey  string `json:"ephemeralKey"`
+	EncCiphertext string `json:"encCiphertext"`
+	OutCiphertext string `json:"outCiphertext"`
+	Proof         string `json:"proof"`
+}
+
+type joinSplitJSON struct {
+	VpubOld     string   `json:"vpub_old"`
+	VpubNew     string   `json:"vpub_new"`
+	Anchor      string   `json:"anchor"`
+	Nullifiers  []string `json:"nullifiers"`
+	Commitments []string `json:"commitments"`
+	OneTimePub  string   `json:"onetimePubKey"`
+	RandomSeed  string   `json:"randomSeed"`
+	Macs        []string `json:"macs"`
+	Proof       string   `json:"proof"`
+	Ciphertexts []string `json:"ciphertexts"`
+}
+
+type txJSON struct {
+	TxID           string `json:"txid"`
+	Version        uint32 `json:"version"`
+	Overwintered   bool   `json:"fOverwintered,omitempty"`
+	VersionGroupID uint32 `json:"nVersionGroupId,omitempty"`
+
+	// Vin holds each input already marshaled to its coinbase or regular
+	// shape, so the two variants don't have to be forced into one
+	// struct's set of omitempty fields.
+	Vin  []json.RawMessage `json:"vin"`
+	Vout []txOutJSON       `json:"vout"`
+
+	LockTime     uint32 `json:"locktime"`
+	ExpiryHeight uint32 `json:"nExpiryHeight,omitempty"`
+
+	ValueBalance    string                  `json:"valueBalance,omitempty"`
+	ShieldedSpends  []spendDescriptionJSON  `json:"vShieldedSpend,omitempty"`
+	ShieldedOutputs []outputDescriptionJSON `json:"vShieldedOutput,omitempty"`
+
+	JoinSplits []joinSplitJSON `json:"vjoinsplit,omitempty"`
+	JSPubKey   string          `json:"joinSplitPubKey,omitempty"`
+	JSSig      string          `json:"joinSplitSig,omitempty"`
+
+	BindingSig string `json:"bindingSig,omitempty"`
+}
+
+func hexEnc(b []byte) string { return hex.EncodeToString(b) }
+
+func hexDec(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return hex.DecodeString(s)
+}
+
+func (t *Tx) MarshalJSON() ([]byte, error) {
+	aux := txJSON{
+		TxID:           t.HexHash(),
+		Version:        t.Version,
+		Overwintered:   t.Overwintered,
+		VersionGroupID: t.VersionGroupID,
+		LockTime:       t.LockTime,
+		ExpiryHeight:   t.ExpiryHeight,
+	}
+
+	for _, in := range t.Inputs {
+		var raw []byte
+		var err error
+		if in.PrevTx == nil && in.PrevTxIndex == 0xffffffff {
+			raw, err = json.Marshal(coinbaseVinJSON{
+				Coinbase: hexEnc(in.Script),
+				Sequence: in.SeqNo,
+			})
+		} else {
+			var txid string
+			if in.PrevTx != nil {
+				txid = hexEnc(revString(cidToHash(in.PrevTx)))
+			}
+			raw, err = json.Marshal(regularVinJSON{
+				TxID:      txid,
+				Vout:      in.PrevTxIndex,
+				ScriptSig: scriptSigJSON{Hex: hexEnc(in.Script)},
+				Sequence:  in.SeqNo,
+			})
+		}
+		if err != nil {
+			return nil, err
+		}
+		aux.Vin = append(aux.Vin, raw)
+	}
+
+	for n, out := range t.Outputs {
+		aux.Vout = append(aux.Vout, txOutJSON{
+			Value:        zatToZecString(out.Value),
+			ValueZat:     out.Value,
+			N:            n,
+			ScriptPubKey: scriptPubKeyJSON{Hex: hexEnc(out.Script)},
+		})
+	}
+
+	if t.Version >= 4 {
+		aux.ValueBalance = signedZatToZecString(t.ValueBalance)
+
+		for _, sp := range t.ShieldedSpends {
+			aux.ShieldedSpends = append(aux.ShieldedSpends, spendDescriptionJSON{
+				CV:           hexEnc(sp.CV),
+				Anchor:       hexEnc(sp.Anchor),
+				Nullifier:    hexEnc(sp.Nullifier),
+				RK:           hexEnc(sp.RK),
+				Proof:        hexEnc(sp.ZKProof),
+				SpendAuthSig: hexEnc(sp.SpendAuthSig),
+			})
+		}
+
+		for _, out := range t.ShieldedOutputs {
+			aux.ShieldedOutputs = append(aux.ShieldedOutputs, outputDescriptionJSON{
+				CV:            hexEnc(out.CV),
+				Cmu:           hexEnc(out.Cmu),
+				EphemeralKey:  hexEnc(out.EphemeralKey),
+				EncCiphertext: hexEnc(out.EncCiphertext),
+				OutCiphertext: hexEnc(out.OutCiphertext),
+				Proof:         hexEnc(out.ZKProof),
+			})
+		}
+
+		if len(t.ShieldedSpends) > 0 || len(t.ShieldedOutputs) > 0 {
+			aux.BindingSig = hexEnc(t.BindingSig)
+		}
+	}
+
+	for _, js := range t.JoinSplits {
+		jsj := joinSplitJSON{
+			VpubOld:    zatToZecString(js.VpubOld),
+			VpubNew:    zatToZecString(js.VpubNew),
+			Anchor:     hexEnc(js.Anchor),
+			OneTimePub: hexEnc(js.EphemeralKey),
+			RandomSeed: hexEnc(js.RandomSeed),
+			Proof:      hexEnc(js.ZKProof),
+		}
+		for _, n := range js.Nullifiers {
+			jsj.Nullifiers = append(jsj.Nullifiers, hexEnc(n))
+		}
+		for _, c := range js.Commitments {
+			jsj.Commitments = append(jsj.Commitments, hexEnc(c))
+		}
+		for _, m := range js.Macs {
+			jsj.Macs = append(jsj.Macs, hexEnc(m))
+		}
+		for _, c := range js.Ciphertexts {
+			jsj.Ciphertexts = append(jsj.Ciphertexts, hexEnc(c))
+		}
+		aux.JoinSplits = append(aux.JoinSplits, jsj)
+	}
+
+	if len(t.JoinSplits) > 0 {
+		aux.JSPubKey = hexEnc(t.JSPubKey)
+		aux.JSSig = hexEnc(t.JSSig)
+	}
+
+	return json.Marshal(aux)
+}
+
+func (t *Tx) UnmarshalJSON(data []byte) error {
+	var aux txJSON
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	t.Overwintered = aux.Overwintered
+	t.Version = aux.Version
+	t.VersionGroupID = aux.VersionGroupID
+	t.LockTime = aux.LockTime
+	t.ExpiryHeight = aux.ExpiryHeight
+
+	codec := t.codec.orDefault()
+
+	t.Inputs = make([]*TxIn, len(aux.Vin))
+	for i, raw := range aux.Vin {
+		var probe struct {
+			Coinbase *string `json:"coinbase"`
+		}
+		if err := json.Unmarshal(raw, &probe); err != nil {
+			return err
+		}
+
+		if probe.Coinbase != nil {
+			var cv coinbaseVinJSON
+			if err := json.Unmarshal(raw, &cv); err != nil {
+				return err
+			}
+			script, err := hexDec(cv.Coinbase)
+			if err != nil {
+				return err
+			}
+			// A coinbase input's vout is conventionally 0xffffffff on
+			// the wire; zcashd's JSON omits the field entirely, so it
+			// isn't round-tripped through the "coinbase" shape.
+			t.Inputs[i] = &TxIn{PrevTxIndex: 0xffffffff, Script: script, SeqNo: cv.Sequence}
+			continue
+		}
+
+		var vin regularVinJSON
+		if err := json.Unmarshal(raw, &vin); err != nil {
+			return err
+		}
+
+		script, err := hexDec(vin.ScriptSig.Hex)
+		if err != nil {
+			return err
+		}
+
+		in := &TxIn{PrevTxIndex: vin.Vout, Script: script, SeqNo: vin.Sequence}
+		if vin.TxID != "" {
+			hash, err := hexDec(vin.TxID)
+			if err != nil {
+				return err
+			}
+			mhb, err := mh.Encode(revString(hash), codec.Hash)
+			if err != nil {
+				return err
+			}
+			in.PrevTx = cid.NewCidV1(codec.TxCodec, mhb)
+		}
+		t.Inputs[i] = in
+	}
+
+	t.Outputs = make([]*TxOut, len(aux.Vout))
+	for i, vout := range aux.Vout {
+		script, err := hexDec(vout.ScriptPubKey.Hex)
+		if err != nil {
+			return err
+		}
+		t.Outputs[i] = &TxOut{Value: vout.ValueZat, Script: script}
+	}
+
+	if aux.ValueBalance != "" {
+		vb, err := zecStringToSignedZat(aux.ValueBalance)
+		if err != nil {
+			return err
+		}
+		t.ValueBalance = vb
+	}
+
+	t.ShieldedSpends = nil
+	for _, sp := range aux.ShieldedSpends {
+		s := &SpendDescription{}
+		var err error
+		if s.CV, err = hexDec(sp.CV); err != nil {
+			return err
+		}
+		if s.Anchor, err = hexDec(sp.Anchor); err != nil {
+			return err
+		}
+		if s.Nullifier, err = hexDec(sp.Nullifier); err != nil {
+			return err
+		}
+		if s.RK, err = hexDec(sp.RK); err != nil {
+			return err
+		}
+		if s.ZKProof, err = hexDec(sp.Proof); err != nil {
+			return err
+		}
+		if s.SpendAuthSig, err = hexDec(sp.SpendAuthSig); err != nil {
+			return err
+		}
+		t.ShieldedSpends = append(t.ShieldedSpends, s)
+	}
+
+	t.ShieldedOutputs = nil
+	for _, out := range aux.ShieldedOutputs {
+		o := &OutputDescription{}
+		var err error
+		if o.CV, err = hexDec(out.CV); err != nil {
+			return err
+		}
+		if o.Cmu, err = hexDec(out.Cmu); err != nil {
+			return err
+		}
+		if o.EphemeralKey, err = hexDec(out.EphemeralKey); err != nil {
+			return err
+		}
+		if o.EncCiphertext, err = hexDec(out.EncCiphertext); err != nil {
+			return err
+		}
+		if o.OutCiphertext, err = hexDec(out.OutCiphertext); err != nil {
+			return err
+		}
+		if o.ZKProof, err = hexDec(out.Proof); err != nil {
+			return err
+		}
+		t.ShieldedOutputs = append(t.ShieldedOutputs, o)
+	}
+
+	if aux.BindingSig != "" {
+		bs, err := hexDec(aux.BindingSig)
+		if err != nil {
+			return err
+		}
+		t.BindingSig = bs
+	}
+
+	t.JoinSplits = nil
+	for _, jsj := range aux.JoinSplits {
+		js := &JSDescription{}
+		var err error
+
+		if js.VpubOld, err = zecStringToZat(jsj.VpubOld); err != nil {
+			return err
+		}
+		if js.VpubNew, err = zecStringToZat(jsj.VpubNew); err != nil {
+			return err
+		}
+		if js.Anchor, err = hexDec(jsj.Anchor); err != nil {
+			return err
+		}
+		if js.EphemeralKey, err = hexDec(jsj.OneTimePub); err != nil {
+			return err
+		}
+		if js.RandomSeed, err = hexDec(jsj.RandomSeed); err != nil {
+			return err
+		}
+		if js.ZKProof, err = hexDec(jsj.Proof); err != nil {
+			return err
+		}
+		for _, n := range jsj.Nullifiers {
+			b, err := hexDec(n)
+			if err != nil {
+				return err
+			}
+			js.Nullifiers = append(js.Nullifiers, b)
+		}
+		for _, c := range jsj.Commitments {
+			b, err := hexDec(c)
+			if err != nil {
+				return err
+			}
+			js.Commitments = append(js.Commitments, b)
+		}
+		for _, m := range jsj.Macs {
+			b, err := hexDec(m)
+			if err != nil {
+				return err
+			}
+			js.Macs = append(js.Macs, b)
+		}
+		for _, c := range jsj.Ciphertexts {
+			b, err := hexDec(c)
+			if err != nil {
+				return err
+			}
+			js.Ciphertexts = append(js.Ciphertexts, b)
+		}
+
+		t.JoinSplits = append(t.JoinSplits, js)
+	}
+
+	if len(t.JoinSplits) > 0 {
+		var err error
+		if t.JSPubKey, err = hexDec(aux.JSPubKey); err != nil {
+			return err
+		}
+		if t.JSSig, err = hexDec(aux.JSSig); err != nil {
+			return err
+		}
+	}
+
+	t.Invalidate()
+	return nil
+}
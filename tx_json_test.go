@@ -0,0 +1,66 @@
+package ipldzec
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTxJSONRoundTrip(t *testing.T) {
+	want := fixtureSaplingTx()
+
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("MarshalJSON: %v", err)
+	}
+
+	got := &Tx{}
+	if err := json.Unmarshal(data, got); err != nil {
+		t.Fatalf("UnmarshalJSON: %v", err)
+	}
+
+	if want.Cid().String() != got.Cid().String() {
+		t.Fatalf("Cid() not stable across JSON round-trip: want %s got %s", want.Cid(), got.Cid())
+	}
+}
+
+func TestTxJSONCoinbaseRoundTrip(t *testing.T) {
+	want := &Tx{
+		Version: 1,
+		Inputs: []*TxIn{
+			{PrevTxIndex: 0xffffffff, Script: []byte{0x03, 0x01, 0x02, 0x03}, SeqNo: 0xffffffff},
+		},
+		Outputs: []*TxOut{
+			{Value: 1250000000, Script: []byte{0x76, 0xa9, 0x14}},
+		},
+	}
+
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("MarshalJSON: %v", err)
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("unmarshal into map: %v", err)
+	}
+	vin := raw["vin"].([]interface{})[0].(map[string]interface{})
+	if _, hasScriptSig := vin["scriptSig"]; hasScriptSig {
+		t.Fatalf("coinbase input should not emit a scriptSig field, got %v", vin)
+	}
+	if _, hasVout := vin["vout"]; hasVout {
+		t.Fatalf("coinbase input should not emit a vout field, got %v", vin)
+	}
+
+	got := &Tx{}
+	if err := json.Unmarshal(data, got); err != nil {
+		t.Fatalf("UnmarshalJSON: %v", err)
+	}
+
+	if got.Inputs[0].PrevTxIndex != 0xffffffff {
+		t.Fatalf("coinbase PrevTxIndex not preserved across JSON round-trip: got %#x", got.Inputs[0].PrevTxIndex)
+	}
+
+	if want.Cid().String() != got.Cid().String() {
+		t.Fatalf("Cid() not stable across JSON round-trip for coinbase tx: want %s got %s", want.Cid(), got.Cid())
+	}
+}
@@ -0,0 +1,121 @@
+package ipldzec
+
+import (
+	"bytes"
+	"testing"
+)
+
+func fixtureSaplingTx() *Tx {
+	return &Tx{
+		Overwintered:   true,
+		Version:        4,
+		VersionGroupID: saplingVersionGroupID,
+		Inputs: []*TxIn{
+			{PrevTxIndex: 0xffffffff, Script: []byte{0xde, 0xad, 0xbe, 0xef}, SeqNo: 0xffffffff},
+		},
+		Outputs: []*TxOut{
+			{Value: 625000000, Script: []byte{0x76, 0xa9, 0x14}},
+		},
+		LockTime:     123456,
+		ExpiryHeight: 123556,
+		ValueBalance: -100000,
+		ShieldedSpends: []*SpendDescription{{
+			CV:           bytes.Repeat([]byte{0x01}, 32),
+			Anchor:       bytes.Repeat([]byte{0x02}, 32),
+			Nullifier:    bytes.Repeat([]byte{0x03}, 32),
+			RK:           bytes.Repeat([]byte{0x04}, 32),
+			ZKProof:      bytes.Repeat([]byte{0x05}, saplingSpendProofSize),
+			SpendAuthSig: bytes.Repeat([]byte{0x06}, 64),
+		}},
+		ShieldedOutputs: []*OutputDescription{{
+			CV:            bytes.Repeat([]byte{0x07}, 32),
+			Cmu:           bytes.Repeat([]byte{0x08}, 32),
+			EphemeralKey:  bytes.Repeat([]byte{0x09}, 32),
+			EncCiphertext: bytes.Repeat([]byte{0x0a}, saplingEncCiphertext),
+			OutCiphertext: bytes.Repeat([]byte{0x0b}, saplingOutCiphertext),
+			ZKProof:       bytes.Repeat([]byte{0x0c}, saplingOutputProofSize),
+		}},
+		BindingSig: bytes.Repeat([]byte{0x0d}, 64),
+	}
+}
+
+func TestTxDecodeRawDataRoundTrip(t *testing.T) {
+	want := fixtureSaplingTx()
+	raw := want.RawData()
+
+	got, err := DecodeTxBytes(raw)
+	if err != nil {
+		t.Fatalf("DecodeTxBytes: %v", err)
+	}
+
+	if !bytes.Equal(got.RawData(), raw) {
+		t.Fatalf("decoded tx re-encodes differently:\nwant %x\ngot  %x", raw, got.RawData())
+	}
+
+	if want.Cid().String() != got.Cid().String() {
+		t.Fatalf("Cid() not stable across decode round-trip: want %s got %s", want.Cid(), got.Cid())
+	}
+}
+
+func TestTxV3OverwinterRoundTrip(t *testing.T) {
+	want := &Tx{
+		Overwintered:   true,
+		Version:        3,
+		VersionGroupID: overwinterVersionGroupID,
+		Inputs: []*TxIn{
+			{PrevTxIndex: 0xffffffff, Script: []byte{0xca, 0xfe}, SeqNo: 0xffffffff},
+		},
+		Outputs: []*TxOut{
+			{Value: 500000000, Script: []byte{0x76, 0xa9, 0x14}},
+		},
+		LockTime:     654321,
+		ExpiryHeight: 654421,
+	}
+
+	got, err := DecodeTxBytes(want.RawData())
+	if err != nil {
+		t.Fatalf("DecodeTxBytes: %v", err)
+	}
+
+	if !bytes.Equal(got.RawData(), want.RawData()) {
+		t.Fatalf("v3 tx did not round-trip")
+	}
+
+	if !got.Overwintered || got.Version != 3 || got.VersionGroupID != overwinterVersionGroupID {
+		t.Fatalf("v3 Overwinter fields not preserved: %+v", got)
+	}
+
+	if got.ExpiryHeight != want.ExpiryHeight {
+		t.Fatalf("ExpiryHeight not preserved: want %d got %d", want.ExpiryHeight, got.ExpiryHeight)
+	}
+
+	if len(got.ShieldedSpends) != 0 || len(got.ShieldedOutputs) != 0 {
+		t.Fatalf("v3 tx should carry no Sapling shielded data, got %+v", got)
+	}
+
+	if want.Cid().String() != got.Cid().String() {
+		t.Fatalf("Cid() not stable across decode round-trip: want %s got %s", want.Cid(), got.Cid())
+	}
+}
+
+func TestTxV1NoShieldedData(t *testing.T) {
+	want := &Tx{
+		Version: 1,
+		Inputs: []*TxIn{
+			{PrevTxIndex: 0, Script: []byte{0x51}, SeqNo: 0xffffffff},
+		},
+		Outputs: []*TxOut{
+			{Value: 1000, Script: []byte{0x52}},
+		},
+		LockTime: 0,
+	}
+
+	got, err := DecodeTxBytes(want.RawData())
+	if err != nil {
+		t.Fatalf("DecodeTxBytes: %v", err)
+	}
+
+	if !bytes.Equal(got.RawData(), want.RawData()) {
+		t.Fatalf("v1 tx did not round-trip")
+	}
+}